@@ -0,0 +1,476 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake stands up an in-process HTTP server implementing the subset of the SSM
+// JSON-1.1 wire protocol exercised by the parameterstore provider: GetParameter,
+// GetParametersByPath, DescribeParameters and GetParameterHistory.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Fixture describes the parameters a Server is seeded with. It is unmarshaled from either
+// YAML or JSON via sigs.k8s.io/yaml, so fixture files may use either format.
+type Fixture struct {
+	Parameters []FixtureParameter `json:"parameters"`
+}
+
+// FixtureParameter describes a single parameter and its version history.
+type FixtureParameter struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	KeyID    string            `json:"keyId"`
+	Tags     map[string]string `json:"tags"`
+	Versions []FixtureVersion  `json:"versions"`
+}
+
+// FixtureVersion describes one historical value of a parameter.
+type FixtureVersion struct {
+	Value            string `json:"value"`
+	Label            string `json:"label"`
+	LastModifiedDate string `json:"lastModifiedDate"`
+}
+
+// recordedRequest captures one inbound call for later assertions.
+type recordedRequest struct {
+	action string
+	body   map[string]interface{}
+}
+
+// failure describes a number of times an action should fail before succeeding.
+type failure struct {
+	errType string
+	message string
+	status  int
+	times   int
+}
+
+// Server is an in-process stand-in for the SSM API.
+type Server struct {
+	URL string
+
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	params   map[string]*FixtureParameter
+	requests []recordedRequest
+	failNext map[string]*failure
+	pageSize int
+}
+
+// Option configures a Server at construction time.
+type Option func(*Server)
+
+// WithPageSize caps GetParametersByPath, DescribeParameters and GetParameterHistory responses at
+// n results per page, so callers can exercise NextToken pagination without huge fixtures.
+func WithPageSize(n int) Option {
+	return func(s *Server) { s.pageSize = n }
+}
+
+// New starts a Server seeded from the fixture at path.
+func New(fixturePath string, opts ...Option) (*Server, error) {
+	raw, err := os.ReadFile(fixturePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read fixture %s: %w", fixturePath, err)
+	}
+	var fixture Fixture
+	if err := yaml.Unmarshal(raw, &fixture); err != nil {
+		return nil, fmt.Errorf("unable to parse fixture %s: %w", fixturePath, err)
+	}
+	s := &Server{
+		params:   make(map[string]*FixtureParameter, len(fixture.Parameters)),
+		failNext: make(map[string]*failure),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for i := range fixture.Parameters {
+		p := fixture.Parameters[i]
+		if p.Type == "" {
+			p.Type = "String"
+		}
+		s.params[p.Name] = &p
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.srv.URL
+	return s, nil
+}
+
+// Close shuts down the underlying HTTP server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// SimulateThrottle makes the next n requests for action fail with ThrottlingException.
+func (s *Server) SimulateThrottle(action string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext[action] = &failure{
+		errType: "ThrottlingException",
+		message: "Rate exceeded",
+		status:  http.StatusBadRequest,
+		times:   n,
+	}
+}
+
+// SimulateExpiredToken makes the next n requests for action fail with ExpiredTokenException.
+func (s *Server) SimulateExpiredToken(action string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext[action] = &failure{
+		errType: "ExpiredTokenException",
+		message: "The security token included in the request is expired",
+		status:  http.StatusBadRequest,
+		times:   n,
+	}
+}
+
+// AssertRequested reports whether any recorded request for action satisfies predicate, which
+// receives the decoded JSON request body (e.g. to check that WithDecryption was sent as true).
+func (s *Server) AssertRequested(action string, predicate func(body map[string]interface{}) bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.requests {
+		if r.action == action && predicate(r.body) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	action := actionFromTarget(r.Header.Get("X-Amz-Target"))
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "SerializationException", err.Error())
+		return
+	}
+	var decoded map[string]interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			respondError(w, http.StatusBadRequest, "SerializationException", err.Error())
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, recordedRequest{action: action, body: decoded})
+	if f, ok := s.failNext[action]; ok && f.times > 0 {
+		f.times--
+		if f.times == 0 {
+			delete(s.failNext, action)
+		}
+		s.mu.Unlock()
+		respondError(w, f.status, f.errType, f.message)
+		return
+	}
+	s.mu.Unlock()
+
+	switch action {
+	case "GetParameter":
+		s.handleGetParameter(w, decoded)
+	case "GetParametersByPath":
+		s.handleGetParametersByPath(w, decoded)
+	case "DescribeParameters":
+		s.handleDescribeParameters(w, decoded)
+	case "GetParameterHistory":
+		s.handleGetParameterHistory(w, decoded)
+	default:
+		respondError(w, http.StatusBadRequest, "UnknownOperationException", "unsupported action: "+action)
+	}
+}
+
+func (s *Server) handleGetParameter(w http.ResponseWriter, body map[string]interface{}) {
+	name, _ := body["Name"].(string)
+	key, versionOrLabel := splitVersion(name)
+
+	s.mu.Lock()
+	p, ok := s.params[key]
+	s.mu.Unlock()
+	if !ok {
+		respondError(w, http.StatusBadRequest, "ParameterNotFound", fmt.Sprintf("parameter %s not found", key))
+		return
+	}
+	version, ok := resolveVersion(p, versionOrLabel)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "ParameterVersionNotFound", fmt.Sprintf("version %s of parameter %s not found", versionOrLabel, key))
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"Parameter": parameterJSON(p, len(p.Versions), version),
+	})
+}
+
+func (s *Server) handleGetParametersByPath(w http.ResponseWriter, body map[string]interface{}) {
+	path, _ := body["Path"].(string)
+	recursive, _ := body["Recursive"].(bool)
+
+	s.mu.Lock()
+	names := make([]string, 0, len(s.params))
+	for name := range s.params {
+		if name != path && !strings.HasPrefix(name, strings.TrimSuffix(path, "/")+"/") {
+			continue
+		}
+		rest := strings.TrimPrefix(strings.TrimPrefix(name, path), "/")
+		if !recursive && strings.Contains(rest, "/") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	start, end, next, err := s.paginate(len(names), requestToken(body))
+	if err != nil {
+		s.mu.Unlock()
+		respondError(w, http.StatusBadRequest, "ValidationException", err.Error())
+		return
+	}
+	params := make([]map[string]interface{}, 0, end-start)
+	for _, name := range names[start:end] {
+		p := s.params[name]
+		params = append(params, parameterJSON(p, len(p.Versions), len(p.Versions)))
+	}
+	s.mu.Unlock()
+
+	respondJSON(w, http.StatusOK, withNextToken(map[string]interface{}{"Parameters": params}, next))
+}
+
+func (s *Server) handleDescribeParameters(w http.ResponseWriter, body map[string]interface{}) {
+	filters := parameterFilters(body)
+
+	s.mu.Lock()
+	names := make([]string, 0, len(s.params))
+	for name, p := range s.params {
+		if !matchesFilters(p, filters) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	start, end, next, err := s.paginate(len(names), requestToken(body))
+	if err != nil {
+		s.mu.Unlock()
+		respondError(w, http.StatusBadRequest, "ValidationException", err.Error())
+		return
+	}
+	metas := make([]map[string]interface{}, 0, end-start)
+	for _, name := range names[start:end] {
+		p := s.params[name]
+		metas = append(metas, map[string]interface{}{
+			"Name": p.Name,
+			"Type": p.Type,
+		})
+	}
+	s.mu.Unlock()
+
+	respondJSON(w, http.StatusOK, withNextToken(map[string]interface{}{"Parameters": metas}, next))
+}
+
+func (s *Server) handleGetParameterHistory(w http.ResponseWriter, body map[string]interface{}) {
+	name, _ := body["Name"].(string)
+
+	s.mu.Lock()
+	p, ok := s.params[name]
+	if !ok {
+		s.mu.Unlock()
+		respondError(w, http.StatusBadRequest, "ParameterNotFound", fmt.Sprintf("parameter %s not found", name))
+		return
+	}
+	start, end, next, err := s.paginate(len(p.Versions), requestToken(body))
+	if err != nil {
+		s.mu.Unlock()
+		respondError(w, http.StatusBadRequest, "ValidationException", err.Error())
+		return
+	}
+	history := make([]map[string]interface{}, 0, end-start)
+	for i := start; i < end; i++ {
+		history = append(history, parameterJSON(p, len(p.Versions), i+1))
+	}
+	s.mu.Unlock()
+
+	respondJSON(w, http.StatusOK, withNextToken(map[string]interface{}{"Parameters": history}, next))
+}
+
+// requestToken extracts the caller-supplied NextToken from a decoded request body, if any.
+func requestToken(body map[string]interface{}) *string {
+	token, ok := body["NextToken"].(string)
+	if !ok || token == "" {
+		return nil
+	}
+	return &token
+}
+
+// withNextToken adds NextToken to resp when next is non-nil.
+func withNextToken(resp map[string]interface{}, next *string) map[string]interface{} {
+	if next != nil {
+		resp["NextToken"] = *next
+	}
+	return resp
+}
+
+// paginate computes the [start:end) window of total results to return for nextToken, given the
+// server's configured page size (0 means unlimited, i.e. everything in one page), and the
+// NextToken to hand back if results remain beyond end.
+func (s *Server) paginate(total int, nextToken *string) (start, end int, next *string, err error) {
+	if nextToken != nil {
+		start, err = strconv.Atoi(*nextToken)
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("invalid NextToken %q", *nextToken)
+		}
+	}
+	if start > total {
+		start = total
+	}
+	end = total
+	if s.pageSize > 0 && start+s.pageSize < total {
+		end = start + s.pageSize
+		token := strconv.Itoa(end)
+		next = &token
+	}
+	return start, end, next, nil
+}
+
+// parameterFilter is a decoded ssm.ParameterStringFilter.
+type parameterFilter struct {
+	key    string
+	values []string
+}
+
+// parameterFilters decodes the ParameterFilters sent with DescribeParameters.
+func parameterFilters(body map[string]interface{}) []parameterFilter {
+	raw, _ := body["ParameterFilters"].([]interface{})
+	filters := make([]parameterFilter, 0, len(raw))
+	for _, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := m["Key"].(string)
+		valuesRaw, _ := m["Values"].([]interface{})
+		values := make([]string, 0, len(valuesRaw))
+		for _, v := range valuesRaw {
+			if str, ok := v.(string); ok {
+				values = append(values, str)
+			}
+		}
+		filters = append(filters, parameterFilter{key: key, values: values})
+	}
+	return filters
+}
+
+// matchesFilters reports whether p satisfies every `tag:<name>` filter with Equals semantics,
+// the only kind the provider sends; non-tag filters are ignored rather than rejected.
+func matchesFilters(p *FixtureParameter, filters []parameterFilter) bool {
+	for _, f := range filters {
+		tagName := strings.TrimPrefix(f.key, "tag:")
+		if tagName == f.key {
+			continue
+		}
+		val, ok := p.Tags[tagName]
+		if !ok {
+			return false
+		}
+		found := false
+		for _, v := range f.values {
+			if v == val {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// splitVersion splits a `name:version` or `name:label` request into its parts.
+func splitVersion(name string) (string, string) {
+	idx := strings.LastIndex(name, ":")
+	if idx == -1 {
+		return name, ""
+	}
+	return name[:idx], name[idx+1:]
+}
+
+// resolveVersion returns the 1-indexed version number matching versionOrLabel, defaulting to
+// the latest version when versionOrLabel is empty.
+func resolveVersion(p *FixtureParameter, versionOrLabel string) (int, bool) {
+	if len(p.Versions) == 0 {
+		return 0, false
+	}
+	if versionOrLabel == "" {
+		return len(p.Versions), true
+	}
+	for i, v := range p.Versions {
+		if fmt.Sprintf("%d", i+1) == versionOrLabel || v.Label == versionOrLabel {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func parameterJSON(p *FixtureParameter, _, version int) map[string]interface{} {
+	v := p.Versions[version-1]
+	out := map[string]interface{}{
+		"Name":    p.Name,
+		"Type":    p.Type,
+		"Value":   v.Value,
+		"Version": version,
+	}
+	if v.LastModifiedDate != "" {
+		out["LastModifiedDate"] = v.LastModifiedDate
+	}
+	if p.KeyID != "" {
+		out["Type"] = "SecureString"
+		out["Value"] = v.Value
+	}
+	return out
+}
+
+// actionFromTarget extracts the action name from an X-Amz-Target header, e.g.
+// "AmazonSSM.GetParameter" -> "GetParameter".
+func actionFromTarget(target string) string {
+	idx := strings.LastIndex(target, ".")
+	if idx == -1 {
+		return target
+	}
+	return target[idx+1:]
+}
+
+func respondJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func respondError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"__type":  errType,
+		"message": message,
+	})
+}
@@ -0,0 +1,205 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fake
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+func newTestClient(t *testing.T, s *Server) *ssm.SSM {
+	t.Helper()
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(s.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("unable to create session: %v", err)
+	}
+	return ssm.New(sess)
+}
+
+func TestGetParameterWithDecryption(t *testing.T) {
+	s, err := New("testdata/parameters.yaml")
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	defer s.Close()
+
+	client := newTestClient(t, s)
+	out, err := client.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String("/dev/app/db/password"),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if aws.StringValue(out.Parameter.Value) != "hunter2" {
+		t.Errorf("expected latest version value, got %q", aws.StringValue(out.Parameter.Value))
+	}
+	if !s.AssertRequested("GetParameter", func(body map[string]interface{}) bool {
+		return body["WithDecryption"] == true
+	}) {
+		t.Errorf("expected WithDecryption=true to have been sent")
+	}
+}
+
+func TestGetParameterByLabel(t *testing.T) {
+	s, err := New("testdata/parameters.yaml")
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	defer s.Close()
+
+	client := newTestClient(t, s)
+	out, err := client.GetParameter(&ssm.GetParameterInput{
+		Name: aws.String("/dev/app/db/password:prod"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if aws.StringValue(out.Parameter.Value) != "hunter2" {
+		t.Errorf("expected label-matched value, got %q", aws.StringValue(out.Parameter.Value))
+	}
+}
+
+func TestGetParametersByPathRecursive(t *testing.T) {
+	s, err := New("testdata/parameters.yaml")
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	defer s.Close()
+
+	client := newTestClient(t, s)
+	out, err := client.GetParametersByPath(&ssm.GetParametersByPathInput{
+		Path:           aws.String("/dev/app"),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.Parameters) != 3 {
+		t.Errorf("expected 3 parameters, got %d", len(out.Parameters))
+	}
+}
+
+func TestGetParametersByPathRespectsSeparatorBoundary(t *testing.T) {
+	s, err := New("testdata/parameters.yaml")
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	defer s.Close()
+
+	client := newTestClient(t, s)
+	out, err := client.GetParametersByPath(&ssm.GetParametersByPathInput{
+		Path:           aws.String("/dev/app"),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range out.Parameters {
+		if aws.StringValue(p.Name) == "/dev/appfoo/x" {
+			t.Errorf("expected /dev/appfoo/x not to match path /dev/app")
+		}
+	}
+}
+
+func TestGetParametersByPathPaginates(t *testing.T) {
+	s, err := New("testdata/parameters.yaml", WithPageSize(1))
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	defer s.Close()
+
+	client := newTestClient(t, s)
+	var names []string
+	var nextToken *string
+	for {
+		out, err := client.GetParametersByPath(&ssm.GetParametersByPathInput{
+			Path:      aws.String("/dev/app"),
+			Recursive: aws.Bool(true),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(out.Parameters) > 1 {
+			t.Errorf("expected at most 1 parameter per page, got %d", len(out.Parameters))
+		}
+		for _, p := range out.Parameters {
+			names = append(names, aws.StringValue(p.Name))
+		}
+		nextToken = out.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	if len(names) != 3 {
+		t.Errorf("expected 3 parameters across all pages, got %d (%v)", len(names), names)
+	}
+}
+
+func TestDescribeParametersFiltersByTag(t *testing.T) {
+	s, err := New("testdata/parameters.yaml")
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	defer s.Close()
+
+	client := newTestClient(t, s)
+	out, err := client.DescribeParameters(&ssm.DescribeParametersInput{
+		ParameterFilters: []*ssm.ParameterStringFilter{
+			{
+				Key:    aws.String("tag:env"),
+				Values: []*string{aws.String("dev")},
+				Option: aws.String("Equals"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.Parameters) != 1 || aws.StringValue(out.Parameters[0].Name) != "/dev/app/db/password" {
+		t.Errorf("expected only /dev/app/db/password to match tag env=dev, got %v", out.Parameters)
+	}
+}
+
+func TestSimulateThrottleThenSucceed(t *testing.T) {
+	s, err := New("testdata/parameters.yaml")
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	defer s.Close()
+	s.SimulateThrottle("GetParameter", 2)
+
+	client := newTestClient(t, s)
+	client.Config.MaxRetries = aws.Int(5)
+	out, err := client.GetParameter(&ssm.GetParameterInput{
+		Name: aws.String("/dev/app/db/username"),
+	})
+	if err != nil {
+		t.Fatalf("expected request to succeed after retries, got: %v", err)
+	}
+	if aws.StringValue(out.Parameter.Value) != "admin" {
+		t.Errorf("unexpected value %q", aws.StringValue(out.Parameter.Value))
+	}
+}
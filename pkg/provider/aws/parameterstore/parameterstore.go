@@ -18,9 +18,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/tidwall/gjson"
@@ -34,34 +39,228 @@ import (
 
 // ParameterStore is a provider for AWS ParameterStore.
 type ParameterStore struct {
+	id     string
 	sess   *session.Session
 	client PMInterface
+
+	// hopARNs and hopCreds mirror AssumeRoleChain so Validate can report which hop in the
+	// chain failed, rather than only the final, possibly unrelated, error.
+	hopARNs  []string
+	hopCreds []*credentials.Credentials
+
+	preservePathHierarchy bool
+}
+
+// ProviderConfig holds the per-instance configuration needed to construct a ParameterStore
+// client, e.g. a distinct session for a different account, region or assumed role.
+type ProviderConfig struct {
+	Session *session.Session
+
+	// AssumeRoleChain lists role ARNs to assume, in order, before talking to SSM.
+	AssumeRoleChain []string
+	// ExternalID is passed to every role assumed in AssumeRoleChain.
+	ExternalID string
+	// RoleSessionName is used for every role assumed in AssumeRoleChain. Defaults to the
+	// provider id when empty.
+	RoleSessionName string
+
+	// PreservePathHierarchy makes findByPath keep `/`-delimited path components in the
+	// resulting secret keys (joined with `.`) instead of collapsing them to the same flat
+	// `_`-joined form findByName/findByTags use.
+	PreservePathHierarchy bool
 }
 
+// registry keeps track of ParameterStore instances registered via RegisterProvider so they
+// can be resolved by ID as part of a Chain.
+var registry = struct {
+	mu        sync.RWMutex
+	providers map[string]*ParameterStore
+}{providers: make(map[string]*ParameterStore)}
+
 // PMInterface is a subset of the parameterstore api.
 // see: https://docs.aws.amazon.com/sdk-for-go/api/service/ssm/ssmiface/
 type PMInterface interface {
 	GetParameter(*ssm.GetParameterInput) (*ssm.GetParameterOutput, error)
+	GetParametersByPath(*ssm.GetParametersByPathInput) (*ssm.GetParametersByPathOutput, error)
+	GetParameterHistory(*ssm.GetParameterHistoryInput) (*ssm.GetParameterHistoryOutput, error)
 	DescribeParameters(*ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error)
 }
 
 const (
 	errUnexpectedFindOperator = "unexpected find operator"
 	errDuplicateKey           = "duplicate key mapping at %s"
+	errVersionNotFound        = "version %s not found for parameter %s"
 )
 
 var log = ctrl.Log.WithName("provider").WithName("aws").WithName("parameterstore")
 
-// New constructs a ParameterStore Provider that is specific to a store.
-func New(sess *session.Session) (*ParameterStore, error) {
+// New constructs a ParameterStore Provider identified by id, so that multiple clients
+// (different accounts/regions/roles) can coexist and be selected by key via RegisterProvider.
+// Assumed-role credentials in cfg.AssumeRoleChain are not resolved here; call Validate to probe
+// them, since doing so in New would make every construction a blocking network round-trip.
+func New(id string, cfg ProviderConfig) (*ParameterStore, error) {
+	sess, hopCreds := assumeRoleChain(id, cfg)
 	return &ParameterStore{
-		sess:   sess,
-		client: ssm.New(sess),
+		id:                    id,
+		sess:                  sess,
+		client:                ssm.New(sess),
+		hopARNs:               cfg.AssumeRoleChain,
+		hopCreds:              hopCreds,
+		preservePathHierarchy: cfg.PreservePathHierarchy,
 	}, nil
 }
 
+// assumeRoleChain wraps cfg.Session with a successive stscreds.AssumeRoleProvider for each
+// role in cfg.AssumeRoleChain, so the final session's credentials resolve through every hop.
+// It returns the per-hop credentials alongside the session so Validate can check each hop
+// individually and report which one failed. Credentials are wired up lazily; no hop is actually
+// assumed until something calls Get (e.g. Validate, or the SSM client on first request).
+func assumeRoleChain(id string, cfg ProviderConfig) (*session.Session, []*credentials.Credentials) {
+	sess := cfg.Session
+	hopCreds := make([]*credentials.Credentials, 0, len(cfg.AssumeRoleChain))
+	sessionName := cfg.RoleSessionName
+	if sessionName == "" {
+		sessionName = id
+	}
+	for _, roleARN := range cfg.AssumeRoleChain {
+		creds := stscreds.NewCredentials(sess, roleARN, func(p *stscreds.AssumeRoleProvider) {
+			p.RoleSessionName = sessionName
+			if cfg.ExternalID != "" {
+				p.ExternalID = aws.String(cfg.ExternalID)
+			}
+		})
+		hopCreds = append(hopCreds, creds)
+		sess = sess.Copy(&aws.Config{Credentials: creds})
+	}
+	return sess, hopCreds
+}
+
+// RegisterProvider constructs a ParameterStore Provider and stores it in the registry under
+// id, so it can later be looked up by a Chain.
+func RegisterProvider(id string, cfg ProviderConfig) (*ParameterStore, error) {
+	pm, err := New(id, cfg)
+	if err != nil {
+		return nil, err
+	}
+	registry.mu.Lock()
+	registry.providers[id] = pm
+	registry.mu.Unlock()
+	return pm, nil
+}
+
+// Chain is an ordered list of provider IDs resolved from the registry. GetSecret, GetSecretMap,
+// GetAllSecrets and Validate walk the chain in order and return the first hit, enabling failover
+// between e.g. staging/prod parameter trees; Close visits every resolved provider.
+type Chain []string
+
+// resolve looks up id in the registry.
+func resolve(id string) (*ParameterStore, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	pm, ok := registry.providers[id]
+	return pm, ok
+}
+
+// GetSecret walks the chain in order, returning the first successful result. If every
+// provider errors, the error from the last hop is returned.
+func (c Chain) GetSecret(ctx context.Context, ref esv1beta1.ExternalSecretDataRemoteRef) ([]byte, error) {
+	var lastErr error
+	for _, id := range c {
+		pm, ok := resolve(id)
+		if !ok {
+			lastErr = fmt.Errorf("no provider registered for id %s", id)
+			continue
+		}
+		val, err := pm.GetSecret(ctx, ref)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return val, nil
+	}
+	return nil, lastErr
+}
+
+// GetSecretMap walks the chain in order, returning the first successful result. If every
+// provider errors, the error from the last hop is returned.
+func (c Chain) GetSecretMap(ctx context.Context, ref esv1beta1.ExternalSecretDataRemoteRef) (map[string][]byte, error) {
+	var lastErr error
+	for _, id := range c {
+		pm, ok := resolve(id)
+		if !ok {
+			lastErr = fmt.Errorf("no provider registered for id %s", id)
+			continue
+		}
+		val, err := pm.GetSecretMap(ctx, ref)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return val, nil
+	}
+	return nil, lastErr
+}
+
+// GetAllSecrets walks the chain in order, returning the first successful result. If every
+// provider errors, the error from the last hop is returned.
+func (c Chain) GetAllSecrets(ctx context.Context, ref esv1beta1.ExternalSecretFind) (map[string][]byte, error) {
+	var lastErr error
+	for _, id := range c {
+		pm, ok := resolve(id)
+		if !ok {
+			lastErr = fmt.Errorf("no provider registered for id %s", id)
+			continue
+		}
+		val, err := pm.GetAllSecrets(ctx, ref)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return val, nil
+	}
+	return nil, lastErr
+}
+
+// Validate reports the chain usable as soon as one hop validates, since that hop alone is
+// enough for GetSecret et al. to succeed. If every provider fails to validate, the error from
+// the last hop is returned.
+func (c Chain) Validate() error {
+	var lastErr error
+	for _, id := range c {
+		pm, ok := resolve(id)
+		if !ok {
+			lastErr = fmt.Errorf("no provider registered for id %s", id)
+			continue
+		}
+		if err := pm.Validate(); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// Close closes every provider in the chain, returning the first error encountered, if any.
+func (c Chain) Close(ctx context.Context) error {
+	var firstErr error
+	for _, id := range c {
+		pm, ok := resolve(id)
+		if !ok {
+			continue
+		}
+		if err := pm.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 // Empty GetAllSecrets.
 func (pm *ParameterStore) GetAllSecrets(ctx context.Context, ref esv1beta1.ExternalSecretFind) (map[string][]byte, error) {
+	if ref.Path != nil {
+		return pm.findByPath(ref)
+	}
 	if ref.Name != nil {
 		return pm.findByName(ref)
 	}
@@ -71,6 +270,46 @@ func (pm *ParameterStore) GetAllSecrets(ctx context.Context, ref esv1beta1.Exter
 	return nil, errors.New(errUnexpectedFindOperator)
 }
 
+// findByPath discovers parameters hierarchically below ref.Path using GetParametersByPath.
+func (pm *ParameterStore) findByPath(ref esv1beta1.ExternalSecretFind) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	var nextToken *string
+	for {
+		it, err := pm.client.GetParametersByPath(&ssm.GetParametersByPathInput{
+			Path:           ref.Path,
+			Recursive:      aws.Bool(true),
+			WithDecryption: aws.Bool(true),
+			NextToken:      nextToken,
+		})
+		if err != nil {
+			return nil, util.SanitizeErr(err)
+		}
+		log.V(1).Info("aws pm findByPath found", "parameters", len(it.Parameters))
+		for _, param := range it.Parameters {
+			key := mapPathSecretKey(*param.Name, pm.preservePathHierarchy)
+			if aws.StringValue(param.Type) == ssm.ParameterTypeStringList {
+				for i, v := range stringListValues(param) {
+					k := fmt.Sprintf("%s_%d", key, i)
+					if _, exists := data[k]; exists {
+						return nil, fmt.Errorf(errDuplicateKey, k)
+					}
+					data[k] = []byte(v)
+				}
+				continue
+			}
+			if _, exists := data[key]; exists {
+				return nil, fmt.Errorf(errDuplicateKey, key)
+			}
+			data[key] = []byte(*param.Value)
+		}
+		nextToken = it.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return data, nil
+}
+
 func (pm *ParameterStore) findByName(ref esv1beta1.ExternalSecretFind) (map[string][]byte, error) {
 	matcher, err := find.New(*ref.Name)
 	if err != nil {
@@ -104,7 +343,7 @@ func (pm *ParameterStore) findByName(ref esv1beta1.ExternalSecretFind) (map[stri
 }
 
 func (pm *ParameterStore) findByTags(ref esv1beta1.ExternalSecretFind) (map[string][]byte, error) {
-	filters := make([]*ssm.ParameterStringFilter, len(ref.Tags))
+	filters := make([]*ssm.ParameterStringFilter, 0, len(ref.Tags))
 	for k, v := range ref.Tags {
 		filters = append(filters, &ssm.ParameterStringFilter{
 			Key:    utilpointer.StringPtr(fmt.Sprintf("tag:%s", k)),
@@ -154,6 +393,13 @@ func (pm *ParameterStore) fetchAndSet(data map[string][]byte, name string) error
 		return fmt.Errorf(errDuplicateKey, key)
 	}
 
+	if aws.StringValue(out.Parameter.Type) == ssm.ParameterTypeStringList {
+		for i, v := range stringListValues(out.Parameter) {
+			data[fmt.Sprintf("%s_%d", key, i)] = []byte(v)
+		}
+		return nil
+	}
+
 	// secret keys must consist of alphanumeric characters or `-`, `_` or `.`
 	data[mapSecretKey(name)] = []byte(*out.Parameter.Value)
 	return nil
@@ -171,33 +417,162 @@ func mapSecretKey(str string) string {
 	return strings.ReplaceAll(str, "/", "_")
 }
 
+// mapPathSecretKey maps a parameter discovered via findByPath to a secret key. When preserveHierarchy
+// is set, `/`-delimited components are joined with `.` instead of collapsed to `mapSecretKey`'s flat
+// `_` form, e.g. `/dev/app/db` -> `dev.app.db`; `.` is used rather than `/` since the latter is not a
+// legal secret key character.
+func mapPathSecretKey(str string, preserveHierarchy bool) string {
+	str = strings.TrimLeft(str, "/")
+	if preserveHierarchy {
+		return strings.ReplaceAll(str, "/", ".")
+	}
+	return strings.ReplaceAll(str, "/", "_")
+}
+
 // GetSecret returns a single secret from the provider.
 func (pm *ParameterStore) GetSecret(ctx context.Context, ref esv1beta1.ExternalSecretDataRemoteRef) ([]byte, error) {
-	log.Info("fetching secret value", "key", ref.Key)
+	log.Info("fetching secret value", "key", ref.Key, "version", ref.Version)
+	param, err := pm.getParameter(ref)
+	if err != nil {
+		return nil, err
+	}
+	return secretValue(param, ref.Property, ref.Key)
+}
+
+// secretValue extracts the requested value out of an already-fetched param, so callers that
+// need the parameter itself (e.g. GetSecretMap, to check Type) don't have to fetch it twice.
+func secretValue(param *ssm.Parameter, property, key string) ([]byte, error) {
+	if aws.StringValue(param.Type) == ssm.ParameterTypeStringList {
+		return stringListValue(param, property)
+	}
+	if property == "" {
+		if param.Value != nil {
+			return []byte(*param.Value), nil
+		}
+		return nil, fmt.Errorf("invalid secret received. parameter value is nil for key: %s", key)
+	}
+	val := gjson.Get(*param.Value, property)
+	if !val.Exists() {
+		return nil, fmt.Errorf("key %s does not exist in secret %s", property, key)
+	}
+	return []byte(val.String()), nil
+}
+
+// stringListValues splits a StringList parameter's Value on its comma separator.
+func stringListValues(param *ssm.Parameter) []string {
+	return strings.Split(aws.StringValue(param.Value), ",")
+}
+
+// stringListValue indexes into a StringList parameter's comma-separated values. Property may
+// be `#` for the element count, or `[N]` for the Nth element; an empty property returns the
+// raw comma-separated value, matching the non-StringList behavior.
+func stringListValue(param *ssm.Parameter, property string) ([]byte, error) {
+	values := stringListValues(param)
+	switch {
+	case property == "":
+		return []byte(aws.StringValue(param.Value)), nil
+	case property == "#":
+		return []byte(strconv.Itoa(len(values))), nil
+	case strings.HasPrefix(property, "[") && strings.HasSuffix(property, "]"):
+		idx, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(property, "["), "]"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid StringList index %q", property)
+		}
+		if idx < 0 || idx >= len(values) {
+			return nil, fmt.Errorf("index %d out of range for StringList of length %d", idx, len(values))
+		}
+		return []byte(values[idx]), nil
+	default:
+		return nil, fmt.Errorf("unsupported property %q for StringList parameter", property)
+	}
+}
+
+// stringListMap expands a StringList parameter's comma-separated values into key_0, key_1, ... entries.
+func stringListMap(param *ssm.Parameter) map[string][]byte {
+	values := stringListValues(param)
+	secretData := make(map[string][]byte, len(values))
+	for i, v := range values {
+		secretData[fmt.Sprintf("key_%d", i)] = []byte(v)
+	}
+	return secretData
+}
+
+// getParameter fetches the parameter for ref.Key, honoring ref.Version if set. SSM accepts
+// both a numeric version and a label using the `name:version` / `name:label` syntax, so the
+// common case is a single GetParameter call. If that fails because the specific version was
+// purged from the parameter itself, we fall back to paging through GetParameterHistory.
+func (pm *ParameterStore) getParameter(ref esv1beta1.ExternalSecretDataRemoteRef) (*ssm.Parameter, error) {
+	name := versionedName(ref.Key, ref.Version)
 	out, err := pm.client.GetParameter(&ssm.GetParameterInput{
-		Name:           &ref.Key,
+		Name:           &name,
 		WithDecryption: aws.Bool(true),
 	})
 	if err != nil {
+		if ref.Version != "" && isParameterVersionNotFound(err) {
+			return pm.getHistoricalParameter(ref)
+		}
 		return nil, util.SanitizeErr(err)
 	}
-	if ref.Property == "" {
-		if out.Parameter.Value != nil {
-			return []byte(*out.Parameter.Value), nil
+	log.V(1).Info("fetched parameter", "key", ref.Key, "version", aws.Int64Value(out.Parameter.Version), "lastModified", aws.TimeValue(out.Parameter.LastModifiedDate))
+	return out.Parameter, nil
+}
+
+// getHistoricalParameter looks up a specific numeric version of ref.Key via GetParameterHistory,
+// since that API is the only way to retrieve a version that is no longer the current value.
+func (pm *ParameterStore) getHistoricalParameter(ref esv1beta1.ExternalSecretDataRemoteRef) (*ssm.Parameter, error) {
+	var nextToken *string
+	for {
+		hist, err := pm.client.GetParameterHistory(&ssm.GetParameterHistoryInput{
+			Name:           &ref.Key,
+			WithDecryption: aws.Bool(true),
+			NextToken:      nextToken,
+		})
+		if err != nil {
+			return nil, util.SanitizeErr(err)
+		}
+		for _, p := range hist.Parameters {
+			if strconv.FormatInt(aws.Int64Value(p.Version), 10) == ref.Version {
+				log.Info("fetched historical parameter", "key", ref.Key, "version", aws.Int64Value(p.Version), "lastModified", aws.TimeValue(p.LastModifiedDate))
+				return p, nil
+			}
+		}
+		nextToken = hist.NextToken
+		if nextToken == nil {
+			break
 		}
-		return nil, fmt.Errorf("invalid secret received. parameter value is nil for key: %s", ref.Key)
 	}
-	val := gjson.Get(*out.Parameter.Value, ref.Property)
-	if !val.Exists() {
-		return nil, fmt.Errorf("key %s does not exist in secret %s", ref.Property, ref.Key)
+	return nil, fmt.Errorf(errVersionNotFound, ref.Version, ref.Key)
+}
+
+// versionedName appends the SSM version/label syntax to name when version is set.
+func versionedName(name, version string) string {
+	if version == "" {
+		return name
 	}
-	return []byte(val.String()), nil
+	return fmt.Sprintf("%s:%s", name, version)
+}
+
+// isParameterVersionNotFound reports whether err is SSM's ParameterVersionNotFound error,
+// as opposed to the parameter itself not existing.
+func isParameterVersionNotFound(err error) bool {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code() == ssm.ErrCodeParameterVersionNotFound
+	}
+	return false
 }
 
 // GetSecretMap returns multiple k/v pairs from the provider.
 func (pm *ParameterStore) GetSecretMap(ctx context.Context, ref esv1beta1.ExternalSecretDataRemoteRef) (map[string][]byte, error) {
 	log.Info("fetching secret map", "key", ref.Key)
-	data, err := pm.GetSecret(ctx, ref)
+	param, err := pm.getParameter(ref)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Property == "" && aws.StringValue(param.Type) == ssm.ParameterTypeStringList {
+		return stringListMap(param), nil
+	}
+	data, err := secretValue(param, ref.Property, ref.Key)
 	if err != nil {
 		return nil, err
 	}
@@ -217,7 +592,14 @@ func (pm *ParameterStore) Close(ctx context.Context) error {
 	return nil
 }
 
+// Validate walks the full assume-role chain, if any, returning a wrapped error indicating
+// which hop failed before falling back to checking the provider's own credentials.
 func (pm *ParameterStore) Validate() error {
+	for i, creds := range pm.hopCreds {
+		if _, err := creds.Get(); err != nil {
+			return fmt.Errorf("assume role chain failed at hop %d (%s): %w", i+1, pm.hopARNs[i], err)
+		}
+	}
 	_, err := pm.sess.Config.Credentials.Get()
 	return err
 }
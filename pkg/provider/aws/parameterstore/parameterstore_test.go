@@ -0,0 +1,289 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package parameterstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	esv1beta1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
+	"github.com/external-secrets/external-secrets/pkg/provider/aws/parameterstore/fake"
+)
+
+func newTestParameterStore(t *testing.T, opts ...fake.Option) *ParameterStore {
+	t.Helper()
+	s, err := fake.New("fake/testdata/parameters.yaml", opts...)
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(s.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("unable to create session: %v", err)
+	}
+	pm, err := New("test", ProviderConfig{Session: sess})
+	if err != nil {
+		t.Fatalf("unable to construct ParameterStore: %v", err)
+	}
+	return pm
+}
+
+func TestFindByPathAgainstFakeServer(t *testing.T) {
+	pm := newTestParameterStore(t)
+
+	data, err := pm.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{
+		Path: aws.String("/dev/app"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := data["dev_app_db_password"]; !ok {
+		t.Errorf("expected key dev_app_db_password, got %v", data)
+	}
+	if _, ok := data["dev_appfoo_x"]; ok {
+		t.Errorf("expected /dev/appfoo/x not to be discovered under path /dev/app")
+	}
+	if string(data["dev_app_api-keys_0"]) != "key-a" {
+		t.Errorf("expected StringList parameter to be expanded to dev_app_api-keys_0, got %v", data)
+	}
+}
+
+func TestGetSecretByLabelAgainstFakeServer(t *testing.T) {
+	pm := newTestParameterStore(t)
+
+	val, err := pm.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{
+		Key:     "/dev/app/db/password",
+		Version: "prod",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(val) != "hunter2" {
+		t.Errorf("expected label-matched value, got %q", val)
+	}
+}
+
+func TestGetSecretByHistoricalVersionAgainstFakeServer(t *testing.T) {
+	pm := newTestParameterStore(t)
+
+	val, err := pm.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{
+		Key:     "/dev/app/db/password",
+		Version: "1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(val) != "hunter1" {
+		t.Errorf("expected first version value, got %q", val)
+	}
+}
+
+func TestStringListAgainstFakeServer(t *testing.T) {
+	pm := newTestParameterStore(t)
+
+	val, err := pm.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{
+		Key:      "/dev/app/api-keys",
+		Property: "[1]",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(val) != "key-b" {
+		t.Errorf("expected key-b, got %q", val)
+	}
+
+	kv, err := pm.GetSecretMap(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{
+		Key: "/dev/app/api-keys",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(kv["key_0"]) != "key-a" {
+		t.Errorf("expected key_0=key-a, got %v", kv)
+	}
+}
+
+func TestFindByPathPaginatesAgainstFakeServer(t *testing.T) {
+	pm := newTestParameterStore(t, fake.WithPageSize(1))
+
+	data, err := pm.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{
+		Path: aws.String("/dev/app"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := data["dev_app_db_password"]; !ok {
+		t.Errorf("expected key dev_app_db_password across pages, got %v", data)
+	}
+	if _, ok := data["dev_app_db_username"]; !ok {
+		t.Errorf("expected key dev_app_db_username across pages, got %v", data)
+	}
+	if string(data["dev_app_api-keys_0"]) != "key-a" {
+		t.Errorf("expected key dev_app_api-keys_0 across pages, got %v", data)
+	}
+}
+
+func TestFindByTagsAgainstFakeServer(t *testing.T) {
+	pm := newTestParameterStore(t)
+
+	data, err := pm.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{
+		Tags: map[string]string{"env": "dev"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(data) != 1 {
+		t.Errorf("expected exactly 1 secret matching tag env=dev, got %v", data)
+	}
+	if _, ok := data["dev_app_db_password"]; !ok {
+		t.Errorf("expected dev_app_db_password to match tag env=dev, got %v", data)
+	}
+}
+
+func TestFindByNameStringListAgainstFakeServer(t *testing.T) {
+	pm := newTestParameterStore(t)
+
+	data, err := pm.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{
+		Name: &esv1beta1.FindName{RegExp: "/dev/app/api-keys"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data["dev_app_api-keys_0"]) != "key-a" {
+		t.Errorf("expected dev_app_api-keys_0=key-a, got %v", data)
+	}
+	if string(data["dev_app_api-keys_1"]) != "key-b" {
+		t.Errorf("expected dev_app_api-keys_1=key-b, got %v", data)
+	}
+}
+
+// registerTestProvider is like newTestParameterStore, but registers the provider under id so
+// it can be resolved by a Chain.
+func registerTestProvider(t *testing.T, id string) *ParameterStore {
+	t.Helper()
+	s, err := fake.New("fake/testdata/parameters.yaml")
+	if err != nil {
+		t.Fatalf("unable to start fake server: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(s.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatalf("unable to create session: %v", err)
+	}
+	pm, err := RegisterProvider(id, ProviderConfig{Session: sess})
+	if err != nil {
+		t.Fatalf("unable to register provider: %v", err)
+	}
+	return pm
+}
+
+func TestChainGetSecretFailsOverPastUnregisteredHop(t *testing.T) {
+	registerTestProvider(t, "chain-secondary")
+	chain := Chain{"chain-missing", "chain-secondary"}
+
+	val, err := chain.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{
+		Key: "/dev/app/db/username",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(val) != "admin" {
+		t.Errorf("expected chain to fall through to the registered hop, got %q", val)
+	}
+}
+
+func TestChainGetSecretReturnsLastErrorWhenAllHopsFail(t *testing.T) {
+	chain := Chain{"chain-missing-1", "chain-missing-2"}
+
+	_, err := chain.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{
+		Key: "/dev/app/db/username",
+	})
+	if err == nil {
+		t.Fatal("expected an error when no hop in the chain resolves")
+	}
+	if err.Error() != "no provider registered for id chain-missing-2" {
+		t.Errorf("expected the last hop's error, got %v", err)
+	}
+}
+
+func TestChainGetSecretMapFailsOverPastUnregisteredHop(t *testing.T) {
+	registerTestProvider(t, "chain-map-secondary")
+	chain := Chain{"chain-missing", "chain-map-secondary"}
+
+	kv, err := chain.GetSecretMap(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{
+		Key: "/dev/app/api-keys",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(kv["key_0"]) != "key-a" {
+		t.Errorf("expected key_0=key-a, got %v", kv)
+	}
+}
+
+func TestChainGetAllSecretsFailsOverPastUnregisteredHop(t *testing.T) {
+	registerTestProvider(t, "chain-find-secondary")
+	chain := Chain{"chain-missing", "chain-find-secondary"}
+
+	data, err := chain.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{
+		Path: aws.String("/dev/app"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := data["dev_app_db_password"]; !ok {
+		t.Errorf("expected key dev_app_db_password, got %v", data)
+	}
+}
+
+func TestChainValidateSucceedsIfAnyHopValidates(t *testing.T) {
+	registerTestProvider(t, "chain-validate-secondary")
+	chain := Chain{"chain-missing", "chain-validate-secondary"}
+
+	if err := chain.Validate(); err != nil {
+		t.Errorf("expected chain to validate via the registered hop, got %v", err)
+	}
+}
+
+func TestChainValidateFailsWhenNoHopResolves(t *testing.T) {
+	chain := Chain{"chain-missing-1", "chain-missing-2"}
+
+	if err := chain.Validate(); err == nil {
+		t.Error("expected an error when no hop in the chain resolves")
+	}
+}
+
+func TestChainCloseClosesEveryResolvedHop(t *testing.T) {
+	registerTestProvider(t, "chain-close-a")
+	registerTestProvider(t, "chain-close-b")
+	chain := Chain{"chain-missing", "chain-close-a", "chain-close-b"}
+
+	if err := chain.Close(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}